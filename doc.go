@@ -28,11 +28,142 @@ time synchronization, and executable permission handling.
 
   - Automatic update checking at configurable intervals
   - SHA256 hash verification for download integrity
+  - Optional detached signature verification (Ed25519, minisign, RSA-PSS)
   - Time synchronization with server to prevent unnecessary updates
   - Automatic executable permission handling (0755)
   - Backup creation before update installation
   - Zero external dependencies (Go standard library only)
 
+# Signature Verification
+
+The deployment API is trusted for release metadata, but not necessarily for binary
+authenticity. If a release includes a SignatureURL, configure a public key so the
+downloaded binary is rejected unless its signature checks out:
+
+	updater.SetPublicKey("ed25519", myPublicKey)
+
+Releases signed with minisign can rotate keys over time; load the key from a minisign
+public key file instead (each call reads the first key in its file and adds it to the trusted
+set, so call it once per key file to trust several keys):
+
+	updater.SetTrustedKeysFile("/etc/myapp/trusted.pub")
+
+Once either of those is configured, a release that omits SignatureURL is rejected rather than
+silently falling back to the SHA256 check alone - see Updater.RequireSignature for why. Call
+RequireSignature(true) to enforce that from startup, before any key has been configured:
+
+	updater.RequireSignature(true)
+
+# Restart Strategies
+
+By default, installing an update terminates the process with os.Exit(0) and leaves
+restarting it to a process manager. Applications that can't tolerate being killed
+mid-request can opt into RestartExec (re-exec in place) or RestartSupervised (run under a
+supervising parent started via Updater.Supervise) instead. Supervise switches the strategy to
+RestartSupervised itself, so it doesn't need to be set separately:
+
+	updater.OnBeforeRestart(func() error {
+		return server.Shutdown(context.Background())
+	})
+	updater.Supervise(func(ctx context.Context) error {
+		return runApp(ctx)
+	})
+
+Under RestartSupervised, the child that runs mainFn is plain exec.Command: a brand new
+process with its own listening sockets. If mainFn calls net.Listen itself, every restart
+still has a gap (or an "address already in use" race) between the old child releasing the
+port and the new one binding it. For a TCP server that can't tolerate that gap, register its
+addresses with SetListenAddrs before calling Supervise, and have mainFn retrieve the
+listeners from Updater.Listeners instead of calling net.Listen directly:
+
+	updater.SetListenAddrs([]string{":8080"})
+	updater.Supervise(func(ctx context.Context) error {
+		listeners, err := updater.Listeners()
+		if err != nil {
+			return err
+		}
+		return runApp(ctx, listeners[0])
+	})
+
+The supervisor binds those addresses once and passes the same file descriptors to every
+child it spawns, so the listening socket - and its connection backlog - survives a restart
+instead of being torn down and recreated.
+
+# Release Sources
+
+By default, Updater fetches release metadata and binaries from its own deployment API.
+Call SetReleaseSource to pull releases from GitHub or a self-hosted JSON manifest instead:
+
+	updater.SetReleaseSource(&autodeployment.GitHubReleaseSource{
+		Owner: "LucazPlays",
+		Repo:  "AutoDeploymentLib-Go",
+	})
+
+# Canary Health Checks and Rollback
+
+The binary swap itself is platform-safe (on Windows the running executable is renamed aside
+before the new one is moved into place, rather than overwritten in place). On top of that,
+a health check can be registered to confirm the new binary actually starts before the
+process restarts into it; a failing check rolls the previous binary back automatically:
+
+	updater.SetHealthCheckArgs([]string{"--healthcheck"})
+
+Rollback() is also exposed directly for callers that detect a bad update some other way
+(e.g. a crash loop reported by a process supervisor):
+
+	if err := updater.Rollback(); err != nil {
+		log.Printf("rollback failed: %v", err)
+	}
+
+# Download Progress and Bandwidth Limiting
+
+Large releases can be resumed mid-download (when the server advertises Accept-Ranges) and
+report progress as they go:
+
+	updater.SetMaxDownloadRate(512 * 1024) // 512 KiB/s
+	updater.SetProgressReporter(myReporter)
+
+Failed or truncated downloads are retried automatically with exponential backoff before the
+update check gives up for that tick. The rate limit and the progress reporter are passed into
+every Download call via DownloadOptions, whichever ReleaseSource is configured via
+SetReleaseSource; the retry policy is not part of DownloadOptions, and the built-in sources
+each apply it through the same backoff helper. Resuming a partial download, specifically, still depends on the source and server
+supporting Range requests - GitHubReleaseSource and StaticJSONSource restart from scratch on
+retry rather than assume that support.
+
+# Staged Rollouts
+
+A release can restrict itself to a percentage of instances and/or a deployment window via
+RolloutPercent, RolloutSeed, NotBeforeEpochMs and Cooldown. Each instance is deterministically
+bucketed from its hostname (or an explicit ID):
+
+	updater.SetInstanceID(os.Getenv("POD_NAME"))
+
+Use DryRun to see why a given instance would or wouldn't update right now, without installing
+anything:
+
+	decision, err := updater.DryRun(context.Background())
+	fmt.Println(decision.WouldUpdate, decision.Reason)
+
+# Context, Logging, and Observability Hooks
+
+NewWithContext and StartContext bind all of Updater's HTTP calls to a caller-supplied
+context, so update checks respect the same cancellation and deadlines as the rest of the
+application:
+
+	updater := autodeployment.NewWithContext(ctx, "uuid", "key")
+	updater.SetHTTPClient(&http.Client{Timeout: 15 * time.Second})
+	updater.StartContext(ctx)
+
+AddHook registers an EventHook that's notified of each step of the update lifecycle -
+useful for structured logging, metrics, or tracing:
+
+	updater.AddHook(myLoggerHook)
+
+Enable SetTelemetry(true) to additionally have the default API source report fleet state
+(the SHA256 of the latest release the check fetched, OS/arch, outcome) back to the deployment
+API after each check. The running binary itself is not hashed for this report.
+
 # Configuration
 
 Set custom API root:
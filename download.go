@@ -0,0 +1,142 @@
+package autodeployment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ProgressReporter receives callbacks describing the progress of a release download.
+type ProgressReporter interface {
+	// OnStart is called once the total download size is known. total is -1 if the server
+	// didn't report a Content-Length.
+	OnStart(total int64)
+	// OnProgress is called as bytes are written to the destination file. written is the
+	// cumulative byte count, including any bytes resumed from a previous attempt.
+	OnProgress(written, total int64)
+	// OnDone is called once with the final result of the download, nil on success.
+	OnDone(err error)
+}
+
+// SetProgressReporter registers a callback for download progress, useful for showing a
+// progress bar or emitting metrics during a (potentially large) release download.
+func (u *Updater) SetProgressReporter(r ProgressReporter) {
+	u.progressReporter = r
+}
+
+// SetMaxDownloadRate caps release downloads to bytesPerSec, so a background update doesn't
+// saturate a user's uplink. 0 (the default) means unlimited.
+func (u *Updater) SetMaxDownloadRate(bytesPerSec int64) {
+	u.maxDownloadRate = bytesPerSec
+}
+
+// retryWithBackoff calls attempt up to maxAttempts times, waiting 2^(n-1) seconds between
+// attempt n-1 and n, until one succeeds or ctx is cancelled. Shared by every ReleaseSource
+// implementation so retry behavior (and its backoff schedule) doesn't have to be
+// reimplemented per source.
+func retryWithBackoff(ctx context.Context, maxAttempts int, attempt func(attempt int) error) error {
+	var lastErr error
+	made := 0
+attempts:
+	for i := 0; i < maxAttempts; i++ {
+		if i > 0 {
+			timer := time.NewTimer(time.Duration(1<<uint(i-1)) * time.Second)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				lastErr = ctx.Err()
+				break attempts
+			}
+		}
+
+		made++
+		if err := attempt(i); err == nil {
+			return nil
+		} else {
+			lastErr = err
+		}
+	}
+	return fmt.Errorf("download failed after %d attempts: %w", made, lastErr)
+}
+
+// resetWriter rewinds and truncates w if it supports seeking and truncation, for
+// ReleaseSource implementations that can't resume a partial download and need to start over
+// cleanly on retry. It's a no-op for writers that support neither.
+func resetWriter(w io.Writer) {
+	if seeker, ok := w.(io.Seeker); ok {
+		seeker.Seek(0, io.SeekStart)
+	}
+	if truncater, ok := w.(interface{ Truncate(size int64) error }); ok {
+		truncater.Truncate(0)
+	}
+}
+
+// rateLimitWriter wraps w in a token-bucket limiter so throughput never exceeds
+// bytesPerSec. A bytesPerSec of 0 or less disables limiting entirely.
+func rateLimitWriter(w io.Writer, bytesPerSec int64) io.Writer {
+	if bytesPerSec <= 0 {
+		return w
+	}
+	return &tokenBucketWriter{
+		w:           w,
+		bytesPerSec: float64(bytesPerSec),
+		tokens:      float64(bytesPerSec),
+		last:        time.Now(),
+	}
+}
+
+type tokenBucketWriter struct {
+	w           io.Writer
+	bytesPerSec float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func (t *tokenBucketWriter) Write(p []byte) (int, error) {
+	t.wait(len(p))
+	return t.w.Write(p)
+}
+
+// wait blocks until n bytes' worth of tokens are available, refilling the bucket based on
+// elapsed time since the last write.
+func (t *tokenBucketWriter) wait(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.tokens += now.Sub(t.last).Seconds() * t.bytesPerSec
+	if t.tokens > t.bytesPerSec {
+		t.tokens = t.bytesPerSec
+	}
+	t.last = now
+
+	if need := float64(n) - t.tokens; need > 0 {
+		time.Sleep(time.Duration(need / t.bytesPerSec * float64(time.Second)))
+		t.tokens = 0
+		t.last = time.Now()
+		return
+	}
+	t.tokens -= float64(n)
+}
+
+// progressWriter wraps w to report cumulative bytes written through a ProgressReporter.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	reporter ProgressReporter
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if p.reporter != nil {
+		p.reporter.OnProgress(p.written, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,129 @@
+package autodeployment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func newTestAPISource(t *testing.T) *apiReleaseSource {
+	t.Helper()
+	u := &Updater{
+		projectKey: "key",
+		httpClient: http.DefaultClient,
+	}
+	return &apiReleaseSource{u: u}
+}
+
+// TestDownloadAttemptResumesPartialDownload verifies that a prior partial write to a seekable
+// destination is resumed via a Range request, rather than re-downloaded from scratch.
+func TestDownloadAttemptResumesPartialDownload(t *testing.T) {
+	const full = "the complete release binary"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHdr := r.Header.Get("Range")
+		if rangeHdr == "" {
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Write([]byte(full))
+			return
+		}
+		if rangeHdr != "bytes=5-" {
+			t.Errorf("unexpected Range header %q", rangeHdr)
+		}
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(full[5:]))
+	}))
+	defer srv.Close()
+
+	s := newTestAPISource(t)
+	rel := &ReleaseInfo{DownloadURL: srv.URL}
+
+	f, err := os.CreateTemp(t.TempDir(), "resume")
+	if err != nil {
+		t.Fatalf("create temp file: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(full[:5]); err != nil {
+		t.Fatalf("write prefix: %v", err)
+	}
+
+	if _, err := s.downloadAttempt(context.Background(), rel, f, int64(len(full)), true, DownloadOptions{}); err != nil {
+		t.Fatalf("downloadAttempt: %v", err)
+	}
+
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("read result: %v", err)
+	}
+	if string(got) != full {
+		t.Fatalf("got %q, want %q", got, full)
+	}
+}
+
+// TestDownloadAttemptTotalMismatch verifies that a transfer shorter than the expected total
+// (from the HEAD response) is reported as an error instead of silently accepted.
+func TestDownloadAttemptTotalMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("short"))
+	}))
+	defer srv.Close()
+
+	s := newTestAPISource(t)
+	rel := &ReleaseInfo{DownloadURL: srv.URL}
+
+	f, err := os.CreateTemp(t.TempDir(), "mismatch")
+	if err != nil {
+		t.Fatalf("create temp file: %v", err)
+	}
+	defer f.Close()
+
+	_, err = s.downloadAttempt(context.Background(), rel, f, 1000, false, DownloadOptions{})
+	if err == nil {
+		t.Fatal("expected an error when the downloaded size doesn't match the expected total")
+	}
+	if !strings.Contains(err.Error(), "expected 1000") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestRetryWithBackoffSucceedsAfterFailures verifies the shared retry helper keeps trying
+// until an attempt succeeds, without exceeding maxAttempts.
+func TestRetryWithBackoffSucceedsAfterFailures(t *testing.T) {
+	calls := 0
+	err := retryWithBackoff(context.Background(), 3, func(attempt int) error {
+		calls++
+		if attempt < 2 {
+			return errPlaceholder
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("retryWithBackoff: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("got %d calls, want 3", calls)
+	}
+}
+
+func TestRetryWithBackoffGivesUpAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	err := retryWithBackoff(context.Background(), 2, func(int) error {
+		calls++
+		return errPlaceholder
+	})
+	if err == nil {
+		t.Fatal("expected an error after exhausting all attempts")
+	}
+	if calls != 2 {
+		t.Fatalf("got %d calls, want 2", calls)
+	}
+}
+
+var errPlaceholder = &placeholderError{}
+
+type placeholderError struct{}
+
+func (*placeholderError) Error() string { return "placeholder failure" }
@@ -0,0 +1,158 @@
+package autodeployment
+
+import (
+	"context"
+	"runtime"
+)
+
+// EventType identifies the kind of lifecycle event an EventHook is notified about.
+type EventType string
+
+const (
+	// EventCheckStarted fires at the beginning of every update check.
+	EventCheckStarted EventType = "check_started"
+	// EventUpToDate fires when the latest release is no newer than the running binary.
+	EventUpToDate EventType = "up_to_date"
+	// EventCheckSkipped fires when a check can't proceed for a reason other than being
+	// up to date or blocked by a staged rollout - e.g. the release has no SHA256 checksum.
+	// See Event.Err.
+	EventCheckSkipped EventType = "check_skipped"
+	// EventCheckFailed fires when a check aborts because of an unexpected error rather than a
+	// normal "nothing to do" outcome - fetching release metadata, stat'ing the running binary,
+	// downloading the release, or hashing it all fire this. See Event.Err.
+	EventCheckFailed EventType = "check_failed"
+	// EventUpdateAvailable fires once a newer, rollout-eligible release is found, before
+	// it's downloaded.
+	EventUpdateAvailable EventType = "update_available"
+	// EventDownloadProgress fires as the release binary downloads. See Event.Written/Total.
+	EventDownloadProgress EventType = "download_progress"
+	// EventHashMismatch fires when the downloaded file's SHA256 doesn't match ReleaseInfo.
+	EventHashMismatch EventType = "hash_mismatch"
+	// EventVerifyRejected fires when the server-side hash check or the detached signature
+	// check rejects the downloaded file. See Event.Err.
+	EventVerifyRejected EventType = "verify_rejected"
+	// EventSwapped fires once the new binary has been installed, just before the process
+	// restarts into it.
+	EventSwapped EventType = "swapped"
+	// EventRollback fires when a failed health check (or an explicit Rollback call) restores
+	// the previous binary. See Event.Err.
+	EventRollback EventType = "rollback"
+)
+
+// Event describes a single update lifecycle occurrence, passed to every registered
+// EventHook. Fields not relevant to Type are left zero.
+type Event struct {
+	Type EventType
+	// Release is the release metadata this event concerns, when applicable.
+	Release *ReleaseInfo
+	// Written and Total describe progress for EventDownloadProgress; Total is -1 if unknown.
+	Written, Total int64
+	// Err carries the failure behind EventCheckSkipped, EventCheckFailed, EventVerifyRejected
+	// or EventRollback, when applicable.
+	Err error
+}
+
+// EventHook observes update lifecycle events, for logging, metrics, or tracing. Implement it
+// with a *slog.Logger adapter, a Prometheus counter, or an OpenTelemetry span exporter.
+type EventHook interface {
+	HandleEvent(Event)
+}
+
+// emit notifies every registered hook of e, in registration order.
+func (u *Updater) emit(e Event) {
+	for _, h := range u.hooks {
+		h.HandleEvent(e)
+	}
+}
+
+// effectiveProgressReporter combines the user-registered ProgressReporter (if any) with an
+// adapter that turns progress callbacks into EventDownloadProgress events for registered
+// hooks (if any), so both mechanisms can be used independently or together.
+func (u *Updater) effectiveProgressReporter(release *ReleaseInfo) ProgressReporter {
+	var reporters multiProgressReporter
+	if u.progressReporter != nil {
+		reporters = append(reporters, u.progressReporter)
+	}
+	if len(u.hooks) > 0 {
+		reporters = append(reporters, &hookProgressReporter{u: u, release: release})
+	}
+
+	switch len(reporters) {
+	case 0:
+		return nil
+	case 1:
+		return reporters[0]
+	default:
+		return reporters
+	}
+}
+
+// multiProgressReporter fans out progress callbacks to each of its members.
+type multiProgressReporter []ProgressReporter
+
+func (m multiProgressReporter) OnStart(total int64) {
+	for _, r := range m {
+		r.OnStart(total)
+	}
+}
+
+func (m multiProgressReporter) OnProgress(written, total int64) {
+	for _, r := range m {
+		r.OnProgress(written, total)
+	}
+}
+
+func (m multiProgressReporter) OnDone(err error) {
+	for _, r := range m {
+		r.OnDone(err)
+	}
+}
+
+// hookProgressReporter adapts download progress into EventDownloadProgress events.
+type hookProgressReporter struct {
+	u       *Updater
+	release *ReleaseInfo
+}
+
+func (h *hookProgressReporter) OnStart(total int64) {}
+
+func (h *hookProgressReporter) OnProgress(written, total int64) {
+	h.u.emit(Event{Type: EventDownloadProgress, Release: h.release, Written: written, Total: total})
+}
+
+func (h *hookProgressReporter) OnDone(err error) {}
+
+// TelemetryReport summarizes the outcome of an update check, sent back to the deployment API
+// when telemetry is enabled via Updater.SetTelemetry.
+type TelemetryReport struct {
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	Outcome string `json:"outcome"`
+	SHA256  string `json:"sha256,omitempty"`
+}
+
+// releaseTelemetrySink is an optional interface a ReleaseSource can implement to receive
+// telemetry reports. The built-in API source implements it; other sources don't have an
+// equivalent endpoint, so telemetry is silently skipped for them.
+type releaseTelemetrySink interface {
+	SendTelemetry(ctx context.Context, t TelemetryReport) error
+}
+
+// sendTelemetry posts a fleet-state report if telemetry is enabled and the configured
+// release source supports it. Best-effort: errors are discarded since telemetry must never
+// affect the update decision.
+func (u *Updater) sendTelemetry(ctx context.Context, release *ReleaseInfo, outcome string) {
+	if !u.telemetry {
+		return
+	}
+	sink, ok := u.releaseSource.(releaseTelemetrySink)
+	if !ok {
+		return
+	}
+
+	report := TelemetryReport{OS: runtime.GOOS, Arch: runtime.GOARCH, Outcome: outcome}
+	if release != nil {
+		report.SHA256 = release.SHA256
+	}
+	sink.SendTelemetry(ctx, report)
+}
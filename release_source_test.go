@@ -0,0 +1,111 @@
+package autodeployment
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func buildTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("write header %s: %v", name, err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestUnpackExecutableMatchesByName reproduces the goreleaser-style layout where a README
+// sorts before the binary: unpackExecutable must pick the entry matching wantName rather than
+// whichever file happens to come first.
+func TestUnpackExecutableMatchesByName(t *testing.T) {
+	files := map[string]string{
+		"README.md": "not the binary",
+		"myapp":     "the real binary",
+	}
+
+	zipData := buildZip(t, files)
+	var out bytes.Buffer
+	if err := unpackExecutable("myapp_linux_amd64.zip", "myapp", bytes.NewReader(zipData), &out); err != nil {
+		t.Fatalf("unpackExecutable zip: %v", err)
+	}
+	if got := out.String(); got != "the real binary" {
+		t.Fatalf("zip: got %q, want %q", got, "the real binary")
+	}
+
+	tarData := buildTarGz(t, files)
+	out.Reset()
+	if err := unpackExecutable("myapp_linux_amd64.tar.gz", "myapp", bytes.NewReader(tarData), &out); err != nil {
+		t.Fatalf("unpackExecutable tar.gz: %v", err)
+	}
+	if got := out.String(); got != "the real binary" {
+		t.Fatalf("tar.gz: got %q, want %q", got, "the real binary")
+	}
+}
+
+// TestUnpackExecutableFallsBackToFirstFile verifies the first regular file is used when
+// wantName is empty.
+func TestUnpackExecutableFallsBackToFirstFile(t *testing.T) {
+	files := map[string]string{"only.bin": "sole entry"}
+	zipData := buildZip(t, files)
+
+	var out bytes.Buffer
+	if err := unpackExecutable("asset.zip", "", bytes.NewReader(zipData), &out); err != nil {
+		t.Fatalf("unpackExecutable: %v", err)
+	}
+	if got := out.String(); got != "sole entry" {
+		t.Fatalf("got %q, want %q", got, "sole entry")
+	}
+}
+
+func TestArchiveEntryMatches(t *testing.T) {
+	cases := []struct {
+		entryName, wantName string
+		want                bool
+	}{
+		{"myapp", "myapp", true},
+		{"bin/myapp.exe", "myapp", true},
+		{"README.md", "myapp", false},
+		{"myapp", "", false},
+	}
+	for _, c := range cases {
+		if got := archiveEntryMatches(c.entryName, c.wantName); got != c.want {
+			t.Errorf("archiveEntryMatches(%q, %q) = %v, want %v", c.entryName, c.wantName, got, c.want)
+		}
+	}
+}
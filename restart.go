@@ -0,0 +1,242 @@
+package autodeployment
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+)
+
+// RestartStrategy controls what happens after a new binary has been swapped into place.
+type RestartStrategy int
+
+const (
+	// RestartExit terminates the process with os.Exit(0), same as earlier versions of this
+	// library. The host application (or a process manager such as systemd) is responsible
+	// for starting the new binary. This is the default.
+	RestartExit RestartStrategy = iota
+	// RestartExec replaces the current process image with the new binary via syscall.Exec,
+	// preserving the PID. Files opened through Go's os and net packages are close-on-exec, so
+	// apart from stdin, stdout and stderr they are not carried over. Not supported on Windows;
+	// falls back to RestartExit there.
+	RestartExec
+	// RestartSupervised signals the parent process started by Updater.Supervise to relaunch
+	// a fresh child running the new binary, without the parent itself exiting.
+	RestartSupervised
+)
+
+// superviseChildEnvVar marks a process as the supervised child so Supervise knows not to
+// fork again.
+const superviseChildEnvVar = "AUTODEPLOYMENT_SUPERVISED_CHILD"
+
+// restartSentinelExitCode is the exit code a supervised child uses to tell its parent "an
+// update was installed, please relaunch me" as opposed to a normal or crash exit.
+const restartSentinelExitCode = 75
+
+// superviseListenerCountEnvVar tells a supervised child how many inherited listener file
+// descriptors to expect, starting at fd 3 (see Listeners).
+const superviseListenerCountEnvVar = "AUTODEPLOYMENT_SUPERVISED_LISTENER_COUNT"
+
+// SetListenAddrs registers the TCP addresses (e.g. "0.0.0.0:8080") Supervise should bind
+// before forking its first child, so the listening sockets themselves - not just the binary -
+// survive a RestartSupervised update. Without this, each new child starts cold: the old
+// listener is torn down and the new one rebound, which either drops in-flight connections or
+// races with the OS over the port. Call mainFn's Listeners method (via the *Updater it
+// closes over) to retrieve the inherited listeners in the same order they were registered
+// here, instead of calling net.Listen directly.
+func (u *Updater) SetListenAddrs(addrs []string) {
+	u.listenAddrs = addrs
+}
+
+// Listeners returns the TCP listeners registered via SetListenAddrs, in the same order, for
+// use inside the function passed to Supervise. Under RestartSupervised these are the same
+// underlying sockets handed to every child the supervisor spawns - the fd is inherited via
+// cmd.ExtraFiles rather than closed and rebound - so a client connection arriving during the
+// handoff is queued by the kernel instead of refused. Returns nil if SetListenAddrs was never
+// called, or if called outside of Supervise (no AUTODEPLOYMENT_SUPERVISED_LISTENER_COUNT).
+func (u *Updater) Listeners() ([]net.Listener, error) {
+	countStr := os.Getenv(superviseListenerCountEnvVar)
+	if countStr == "" {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", superviseListenerCountEnvVar, err)
+	}
+
+	listeners := make([]net.Listener, count)
+	for i := 0; i < count; i++ {
+		// fd 0-2 are stdin/stdout/stderr; inherited listeners start at fd 3 via
+		// cmd.ExtraFiles, in registration order.
+		f := os.NewFile(uintptr(3+i), fmt.Sprintf("listener-%d", i))
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("inherit listener %d: %w", i, err)
+		}
+		listeners[i] = ln
+	}
+	return listeners, nil
+}
+
+// SetRestartStrategy selects what happens after an update is installed.
+// Default: RestartExit. Note that Supervise forces RestartSupervised regardless of what's set
+// here, since it's the only strategy a supervisor knows how to relaunch from.
+func (u *Updater) SetRestartStrategy(s RestartStrategy) {
+	u.restartStrategy = s
+}
+
+// OnBeforeRestart registers a hook run immediately before the process restarts due to an
+// update, in registration order. Use it to drain in-flight requests, close database
+// connections, or flush logs. If any hook returns an error, the restart is aborted and the
+// update is left installed on disk but not yet running.
+func (u *Updater) OnBeforeRestart(fn func() error) {
+	u.beforeRestartHooks = append(u.beforeRestartHooks, fn)
+}
+
+// restart runs the registered before-restart hooks and then restarts the process according
+// to the configured RestartStrategy.
+func (u *Updater) restart() {
+	for _, hook := range u.beforeRestartHooks {
+		if err := hook(); err != nil {
+			return
+		}
+	}
+
+	switch u.restartStrategy {
+	case RestartExec:
+		u.restartExec()
+	case RestartSupervised:
+		os.Exit(restartSentinelExitCode)
+	default:
+		os.Exit(0)
+	}
+}
+
+// restartExec replaces the current process image in place via syscall.Exec. If that fails
+// (or isn't supported on this platform), it falls back to a plain os.Exit(0).
+func (u *Updater) restartExec() {
+	selfPath, err := os.Executable()
+	if err != nil {
+		os.Exit(0)
+	}
+
+	_ = syscall.Exec(selfPath, os.Args, os.Environ())
+	// syscall.Exec only returns on error (or on platforms where it's unsupported).
+	os.Exit(0)
+}
+
+// Supervise runs mainFn under a restart-safe supervisor process.
+//
+// The first time Supervise runs, it forks a child copy of the current executable and waits
+// for it to exit. The child is the one that actually calls mainFn; it also runs the
+// Updater's usual update loop if Start has been called. When the child decides to install
+// an update under RestartSupervised, it runs its before-restart hooks, exits with a sentinel
+// code, and the parent relaunches a fresh child from the (now updated) executable — without
+// the supervising parent process itself ever restarting.
+//
+// Supervise forces the RestartStrategy to RestartSupervised itself, overriding any prior
+// SetRestartStrategy call: the supervisor only knows how to relaunch a child that exits with
+// the RestartSupervised sentinel code, so a forgotten or mismatched SetRestartStrategy call
+// would otherwise make the child exit via RestartExit's plain os.Exit(0), which
+// superviseParent can't tell apart from mainFn returning cleanly - it would tear down the
+// whole supervisor instead of relaunching, silently losing the in-flight work this mode
+// exists to protect.
+//
+// Supervise blocks for the lifetime of the supervisor (or, in the child, for the lifetime of
+// mainFn) and returns mainFn's error, or the error from the supervised child's exit.
+func (u *Updater) Supervise(mainFn func(ctx context.Context) error) error {
+	u.restartStrategy = RestartSupervised
+
+	if os.Getenv(superviseChildEnvVar) == "1" {
+		return mainFn(context.Background())
+	}
+	return u.superviseParent()
+}
+
+// superviseParent implements the supervisor side of Supervise: it relaunches the child
+// whenever it exits with restartSentinelExitCode, and forwards termination signals so the
+// child shuts down gracefully when the supervisor itself is asked to stop.
+//
+// If SetListenAddrs was called, the listeners are bound once, here, before the first child
+// starts, and their file descriptors are passed to every child spawned afterward via
+// cmd.ExtraFiles. The supervisor itself never accepts on them; it only keeps them open so the
+// same kernel socket - and its connection backlog - survives every child restart instead of
+// being closed and rebound from scratch.
+func (u *Updater) superviseParent() error {
+	selfPath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	listenerFiles, err := u.bindListeners()
+	if err != nil {
+		return fmt.Errorf("supervise: bind listeners: %w", err)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	for {
+		cmd := exec.Command(selfPath, os.Args[1:]...)
+		cmd.Env = append(os.Environ(), superviseChildEnvVar+"=1")
+		if len(listenerFiles) > 0 {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", superviseListenerCountEnvVar, len(listenerFiles)))
+			cmd.ExtraFiles = listenerFiles
+		}
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("supervise: start child: %w", err)
+		}
+
+		waitErr := make(chan error, 1)
+		go func() { waitErr <- cmd.Wait() }()
+
+		select {
+		case sig := <-sigCh:
+			if cmd.Process != nil {
+				cmd.Process.Signal(sig)
+			}
+			<-waitErr
+			return nil
+		case err := <-waitErr:
+			if cmd.ProcessState != nil && cmd.ProcessState.ExitCode() == restartSentinelExitCode {
+				continue
+			}
+			return err
+		}
+	}
+}
+
+// bindListeners binds every address registered via SetListenAddrs and returns the underlying
+// *os.File for each, suitable for cmd.ExtraFiles. The net.Listener values themselves are
+// intentionally leaked (not closed) for the lifetime of the supervisor: closing them would
+// close the only reference to the listening socket once the files are duplicated into a
+// child's fd table.
+func (u *Updater) bindListeners() ([]*os.File, error) {
+	files := make([]*os.File, 0, len(u.listenAddrs))
+	for _, addr := range u.listenAddrs {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("listen %s: %w", addr, err)
+		}
+		tcpLn, ok := ln.(*net.TCPListener)
+		if !ok {
+			return nil, fmt.Errorf("listen %s: not a TCP listener", addr)
+		}
+		f, err := tcpLn.File()
+		if err != nil {
+			return nil, fmt.Errorf("listen %s: get file descriptor: %w", addr, err)
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
@@ -0,0 +1,218 @@
+package autodeployment
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// listenerHelperEnvVar, when set, makes TestMain act as a standalone helper process instead
+// of running the test suite: it reconstructs the listener(s) passed via Listeners(), accepts
+// one connection, and echoes what it reads back to stdout. TestBindListenersAndListeners
+// re-execs the test binary with this set to exercise the real fd hand-off end to end, the
+// same way a supervised child inherits listeners from Updater.Supervise.
+const listenerHelperEnvVar = "AUTODEPLOYMENT_TEST_LISTENER_HELPER"
+
+// superviseHelperEnvVar, when set, makes TestMain act as a standalone helper process that runs
+// Updater.Supervise itself (re-exec'ing further generations of the test binary), instead of
+// running the test suite. TestSuperviseRelaunchesAfterUpdate re-execs the test binary with this
+// set to exercise a real update-and-relaunch cycle end to end. The helper's mainFn
+// deliberately never calls SetRestartStrategy, only Updater.restart() - proving Supervise
+// forces RestartSupervised itself, rather than relying on the caller to also set it.
+const superviseHelperEnvVar = "AUTODEPLOYMENT_TEST_SUPERVISE_HELPER"
+
+// superviseHelperMarkerEnvVar names the file the supervise helper uses to record how many
+// times mainFn has run across process generations.
+const superviseHelperMarkerEnvVar = "AUTODEPLOYMENT_TEST_SUPERVISE_MARKER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(listenerHelperEnvVar) == "1" {
+		os.Exit(runListenerHelper())
+	}
+	if os.Getenv(superviseHelperEnvVar) == "1" {
+		os.Exit(runSuperviseHelper())
+	}
+	os.Exit(m.Run())
+}
+
+func runSuperviseHelper() int {
+	marker := os.Getenv(superviseHelperMarkerEnvVar)
+
+	u := &Updater{}
+	err := u.Supervise(func(ctx context.Context) error {
+		data, _ := os.ReadFile(marker)
+		switch len(data) {
+		case 0:
+			// First generation: record that mainFn ran, then simulate an update having
+			// just been installed.
+			if err := os.WriteFile(marker, []byte("x"), 0644); err != nil {
+				return err
+			}
+			u.restart()
+			return nil
+		case 1:
+			// Second generation, after the relaunch: record it and exit cleanly.
+			return os.WriteFile(marker, []byte("xx"), 0644)
+		default:
+			return fmt.Errorf("mainFn ran a third time unexpectedly")
+		}
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func runListenerHelper() int {
+	u := &Updater{}
+	listeners, err := u.Listeners()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(listeners) != 1 {
+		fmt.Fprintf(os.Stderr, "got %d listeners, want 1\n", len(listeners))
+		return 2
+	}
+
+	conn, err := listeners[0].Accept()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 3
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 4)
+	n, err := conn.Read(buf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 4
+	}
+	os.Stdout.Write(buf[:n])
+	return 0
+}
+
+// TestBindListenersAndListeners exercises the real fd hand-off bindListeners/Listeners
+// implement: bindListeners binds a listener and returns its *os.File for cmd.ExtraFiles;
+// a child process (here, the test binary re-exec'd in helper mode) reconstructs it via
+// Listeners and accepts a connection on it, the same as a supervised child would.
+func TestBindListenersAndListeners(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("os/exec ExtraFiles fd inheritance isn't supported on windows")
+	}
+
+	u := &Updater{listenAddrs: []string{"127.0.0.1:0"}}
+	files, err := u.bindListeners()
+	if err != nil {
+		t.Fatalf("bindListeners: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d listener files, want 1", len(files))
+	}
+	for _, f := range files {
+		defer f.Close()
+	}
+
+	addrLn, err := net.FileListener(files[0])
+	if err != nil {
+		t.Fatalf("inspect bound address: %v", err)
+	}
+	addr := addrLn.Addr().String()
+	addrLn.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(),
+		listenerHelperEnvVar+"=1",
+		fmt.Sprintf("%s=%d", superviseListenerCountEnvVar, len(files)),
+	)
+	cmd.ExtraFiles = files
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start helper: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial inherited listener: %v", err)
+	}
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("write to helper: %v", err)
+	}
+	conn.Close()
+
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("helper process failed: %v (stderr: %s)", err, stderr.String())
+	}
+	if got := stdout.String(); got != "ping" {
+		t.Fatalf("helper echoed %q, want %q", got, "ping")
+	}
+}
+
+// TestBindListenersEmpty verifies bindListeners is a no-op when SetListenAddrs was never
+// called, so RestartSupervised still works for hosts that don't need FD-passing.
+func TestBindListenersEmpty(t *testing.T) {
+	u := &Updater{}
+	files, err := u.bindListeners()
+	if err != nil {
+		t.Fatalf("bindListeners: %v", err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("got %d listener files, want 0", len(files))
+	}
+}
+
+// TestListenersNoCountEnvVar verifies Listeners returns nil rather than erroring when called
+// outside of a supervised child (no SetListenAddrs was configured upstream).
+func TestListenersNoCountEnvVar(t *testing.T) {
+	os.Unsetenv(superviseListenerCountEnvVar)
+	u := &Updater{}
+	listeners, err := u.Listeners()
+	if err != nil {
+		t.Fatalf("Listeners: %v", err)
+	}
+	if listeners != nil {
+		t.Fatalf("got %v, want nil", listeners)
+	}
+}
+
+// TestSuperviseRelaunchesAfterUpdate exercises a real Supervise update-and-relaunch cycle end
+// to end: mainFn never calls SetRestartStrategy, only Updater.restart(). Before Supervise
+// forced RestartSupervised itself, this exact omission made the child exit via plain
+// os.Exit(0), which superviseParent couldn't tell apart from mainFn returning successfully -
+// it tore down the whole supervisor on the very first update instead of relaunching. The
+// helper records, via a marker file, how many times mainFn actually ran; two process
+// generations (marker == "xx") proves the relaunch happened.
+func TestSuperviseRelaunchesAfterUpdate(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "supervise-marker")
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(),
+		superviseHelperEnvVar+"=1",
+		superviseHelperMarkerEnvVar+"="+marker,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("supervise helper failed: %v (stderr: %s)", err, stderr.String())
+	}
+
+	data, err := os.ReadFile(marker)
+	if err != nil {
+		t.Fatalf("read marker: %v", err)
+	}
+	if string(data) != "xx" {
+		t.Fatalf("marker = %q, want %q - mainFn should have run twice across the relaunch", string(data), "xx")
+	}
+}
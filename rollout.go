@@ -0,0 +1,111 @@
+package autodeployment
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+)
+
+// Decision explains whether a check would install an update and why, without actually
+// downloading or installing anything. See Updater.DryRun.
+type Decision struct {
+	// WouldUpdate is true if this instance would install Release right now.
+	WouldUpdate bool
+	// Reason explains the decision, e.g. "already up to date" or "not in current rollout wave".
+	Reason string
+	// Release is the latest release metadata returned by the release source, or nil if it
+	// couldn't be fetched.
+	Release *ReleaseInfo
+}
+
+// SetInstanceID overrides the identifier used to bucket this instance into a release's
+// rollout wave. By default the OS hostname is used, which is unstable in many container
+// setups; set an explicit, stable ID there instead.
+func (u *Updater) SetInstanceID(id string) {
+	u.instanceID = id
+}
+
+// DryRun evaluates the latest release the same way checkAndUpdate would, but stops short of
+// downloading or installing it, returning a Decision describing the outcome. This is useful
+// for debugging staged rollouts: why hasn't a given instance updated yet?
+func (u *Updater) DryRun(ctx context.Context) (*Decision, error) {
+	selfPath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := u.releaseSource.FetchLatest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetch latest release: %w", err)
+	}
+	decision := &Decision{Release: release}
+
+	if release.SHA256 == "" {
+		decision.Reason = "release has no SHA256 checksum"
+		return decision, nil
+	}
+
+	info, err := os.Stat(selfPath)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustedMtime := info.ModTime().UnixMilli() + u.serverTimeOffset
+	if adjustedMtime >= release.LastModifiedEpochMs {
+		decision.Reason = "already up to date"
+		return decision, nil
+	}
+
+	if reason := u.rolloutBlockReason(release, adjustedMtime); reason != "" {
+		decision.Reason = reason
+		return decision, nil
+	}
+
+	decision.WouldUpdate = true
+	decision.Reason = "eligible for update"
+	return decision, nil
+}
+
+// rolloutBlockReason returns why this instance should not install release right now, or ""
+// if it's clear to proceed. lastUpdateEpochMs is the server-adjusted mtime of the binary
+// currently running, used as the cooldown baseline: swapBinary sets a release's mtime to its
+// LastModifiedEpochMs, so this naturally survives the process restart that follows every
+// update, unlike an in-memory timestamp which wouldn't still be around by the next check.
+func (u *Updater) rolloutBlockReason(release *ReleaseInfo, lastUpdateEpochMs int64) string {
+	now := u.GetAdjustedLocalTime()
+
+	if release.NotBeforeEpochMs > 0 && now < release.NotBeforeEpochMs {
+		return "deployment window not yet open"
+	}
+
+	if release.Cooldown > 0 && lastUpdateEpochMs > 0 {
+		cooldownMs := release.Cooldown * 60 * 1000
+		if now-lastUpdateEpochMs < cooldownMs {
+			return "cooldown active since last update"
+		}
+	}
+
+	if release.RolloutPercent > 0 && release.RolloutPercent < 100 {
+		if bucket := u.rolloutBucket(release.RolloutSeed); bucket >= release.RolloutPercent {
+			return "not in current rollout wave"
+		}
+	}
+
+	return ""
+}
+
+// rolloutBucket deterministically maps this instance into a bucket in [0, 100), so the same
+// instance lands in the same wave for a given rolloutSeed every time it checks.
+func (u *Updater) rolloutBucket(rolloutSeed string) int {
+	id := u.instanceID
+	if id == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			id = hostname
+		}
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(u.projectUUID + id + rolloutSeed))
+	return int(h.Sum64() % 100)
+}
@@ -0,0 +1,59 @@
+package autodeployment
+
+import "testing"
+
+func TestRolloutBucketIsDeterministic(t *testing.T) {
+	u := &Updater{projectUUID: "proj-1", instanceID: "instance-a"}
+
+	first := u.rolloutBucket("seed-1")
+	second := u.rolloutBucket("seed-1")
+	if first != second {
+		t.Fatalf("rolloutBucket not stable across calls: %d != %d", first, second)
+	}
+	if first < 0 || first >= 100 {
+		t.Fatalf("rolloutBucket out of range: %d", first)
+	}
+}
+
+func TestRolloutBlockReasonNotBeforeWindow(t *testing.T) {
+	u := &Updater{}
+	release := &ReleaseInfo{NotBeforeEpochMs: u.GetAdjustedLocalTime() + 60*60*1000}
+
+	if reason := u.rolloutBlockReason(release, 0); reason == "" {
+		t.Fatal("expected a block reason before the deployment window opens")
+	}
+}
+
+func TestRolloutBlockReasonCooldown(t *testing.T) {
+	u := &Updater{}
+	now := u.GetAdjustedLocalTime()
+	release := &ReleaseInfo{Cooldown: 30}
+
+	if reason := u.rolloutBlockReason(release, now); reason == "" {
+		t.Fatal("expected cooldown to block an update installed just now")
+	}
+
+	longAgo := now - 31*60*1000
+	if reason := u.rolloutBlockReason(release, longAgo); reason != "" {
+		t.Fatalf("expected cooldown to have elapsed, got block reason %q", reason)
+	}
+
+	if reason := u.rolloutBlockReason(release, 0); reason != "" {
+		t.Fatalf("expected no cooldown baseline to not block, got %q", reason)
+	}
+}
+
+func TestRolloutBlockReasonPercent(t *testing.T) {
+	u := &Updater{projectUUID: "proj-1", instanceID: "instance-a"}
+	bucket := u.rolloutBucket("seed-1")
+
+	excluded := &ReleaseInfo{RolloutPercent: bucket, RolloutSeed: "seed-1"}
+	if reason := u.rolloutBlockReason(excluded, 0); reason == "" {
+		t.Fatalf("expected instance in bucket %d to be excluded by RolloutPercent %d", bucket, bucket)
+	}
+
+	included := &ReleaseInfo{RolloutPercent: 100, RolloutSeed: "seed-1"}
+	if reason := u.rolloutBlockReason(included, 0); reason != "" {
+		t.Fatalf("expected RolloutPercent 100 to include every instance, got %q", reason)
+	}
+}
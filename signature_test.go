@@ -0,0 +1,156 @@
+package autodeployment
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// minisignBlob builds the base64 line of a minisign file: a 2-byte algorithm tag, an 8-byte
+// key ID, and the payload (a signature or public key).
+func minisignBlob(algo string, keyID byte, payload []byte) string {
+	blob := append([]byte(algo), make([]byte, 8)...)
+	blob[2] = keyID
+	blob = append(blob, payload...)
+	return base64.StdEncoding.EncodeToString(blob)
+}
+
+func TestDecodeMinisignLineSkipsComments(t *testing.T) {
+	payload := repeatBytes(ed25519.PublicKeySize, 0xAB)
+	line := minisignBlob(minisignBlobPrefix, 0x42, payload)
+	data := "untrusted comment: minisign public key\n" + line + "\n"
+
+	algo, keyID, got, err := decodeMinisignLine([]byte(data))
+	if err != nil {
+		t.Fatalf("decodeMinisignLine: %v", err)
+	}
+	if algo != minisignBlobPrefix {
+		t.Fatalf("algo = %q, want %q", algo, minisignBlobPrefix)
+	}
+	if len(keyID) != 8 || keyID[0] != 0x42 {
+		t.Fatalf("unexpected key ID %x", keyID)
+	}
+	if string(got) != string(payload) {
+		t.Fatalf("payload mismatch")
+	}
+}
+
+func TestDecodeMinisignLineRejectsGarbage(t *testing.T) {
+	if _, _, _, err := decodeMinisignLine([]byte("not base64 at all !!!")); err == nil {
+		t.Fatal("expected an error decoding invalid base64")
+	}
+	if _, _, _, err := decodeMinisignLine([]byte("untrusted comment: only a comment\n")); err == nil {
+		t.Fatal("expected an error when no blob line is present")
+	}
+	if _, _, _, err := decodeMinisignLine([]byte(base64.StdEncoding.EncodeToString([]byte("short")))); err == nil {
+		t.Fatal("expected an error for a blob shorter than the 10-byte header")
+	}
+}
+
+func TestParseMinisignSignatureRoundTrip(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	sig := ed25519.Sign(priv, []byte("release contents"))
+	line := minisignBlob(minisignBlobPrefix, 0x07, sig)
+
+	keyID, got, err := parseMinisignSignature([]byte(line))
+	if err != nil {
+		t.Fatalf("parseMinisignSignature: %v", err)
+	}
+	if keyID != "0700000000000000" {
+		t.Fatalf("keyID = %q, want %q", keyID, "0700000000000000")
+	}
+	if string(got) != string(sig) {
+		t.Fatalf("signature mismatch")
+	}
+}
+
+func TestParseMinisignSignatureRejectsUnsupportedAlgo(t *testing.T) {
+	line := minisignBlob("ED", 0x01, repeatBytes(ed25519.SignatureSize, 0xCD))
+	if _, _, err := parseMinisignSignature([]byte(line)); err == nil {
+		t.Fatal("expected the prehashed \"ED\" variant to be rejected as unsupported")
+	} else if !strings.Contains(err.Error(), "unsupported minisign algorithm") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseMinisignSignatureRejectsWrongLength(t *testing.T) {
+	line := minisignBlob(minisignBlobPrefix, 0x01, repeatBytes(10, 0xEF))
+	if _, _, err := parseMinisignSignature([]byte(line)); err == nil {
+		t.Fatal("expected a signature of the wrong length to be rejected")
+	}
+}
+
+func repeatBytes(n int, fill byte) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = fill
+	}
+	return b
+}
+
+// TestSignatureRequiredDefaultsToFalse verifies a fresh Updater with no keys configured and
+// RequireSignature never called doesn't demand a signature - the common case for callers who
+// only rely on the SHA256 check.
+func TestSignatureRequiredDefaultsToFalse(t *testing.T) {
+	u := &Updater{}
+	if u.signatureRequired() {
+		t.Fatal("expected signatureRequired to be false with no keys configured")
+	}
+}
+
+// TestSignatureRequiredImpliedBySetPublicKey verifies that configuring a public key implies
+// signature verification is mandatory, even without an explicit RequireSignature(true) call -
+// see Updater.RequireSignature for why.
+func TestSignatureRequiredImpliedBySetPublicKey(t *testing.T) {
+	u := &Updater{}
+	u.SetPublicKey("ed25519", repeatBytes(ed25519.PublicKeySize, 0x01))
+	if !u.signatureRequired() {
+		t.Fatal("expected signatureRequired to be true once a public key is configured")
+	}
+}
+
+// TestSignatureRequiredImpliedBySetTrustedKeysFile mirrors
+// TestSignatureRequiredImpliedBySetPublicKey for the minisign trusted-keys-file path.
+func TestSignatureRequiredImpliedBySetTrustedKeysFile(t *testing.T) {
+	u := &Updater{trustedKeys: map[string][]byte{"deadbeef": repeatBytes(ed25519.PublicKeySize, 0x02)}}
+	if !u.signatureRequired() {
+		t.Fatal("expected signatureRequired to be true once trusted keys are configured")
+	}
+}
+
+// TestSignatureRequiredExplicitOverride verifies RequireSignature(true) makes verification
+// mandatory even before any key has been configured.
+func TestSignatureRequiredExplicitOverride(t *testing.T) {
+	u := &Updater{}
+	u.RequireSignature(true)
+	if !u.signatureRequired() {
+		t.Fatal("expected signatureRequired to be true after RequireSignature(true)")
+	}
+}
+
+// TestVerifyReleaseSignatureRejectsMissingURLWhenRequired is the core regression test for the
+// fail-closed policy: a release that omits SignatureURL must be rejected once a key is
+// configured, not silently accepted on the strength of its SHA256 alone.
+func TestVerifyReleaseSignatureRejectsMissingURLWhenRequired(t *testing.T) {
+	u := &Updater{}
+	u.SetPublicKey("ed25519", repeatBytes(ed25519.PublicKeySize, 0x03))
+
+	err := u.verifyReleaseSignature(context.Background(), "unused", &ReleaseInfo{})
+	if err == nil {
+		t.Fatal("expected verifyReleaseSignature to reject a release with no SignatureURL once a key is configured")
+	}
+}
+
+// TestVerifyReleaseSignatureAllowsMissingURLWhenNotRequired verifies the common, unsigned-only
+// configuration (no keys, no RequireSignature) still passes releases through untouched.
+func TestVerifyReleaseSignatureAllowsMissingURLWhenNotRequired(t *testing.T) {
+	u := &Updater{}
+	if err := u.verifyReleaseSignature(context.Background(), "unused", &ReleaseInfo{}); err != nil {
+		t.Fatalf("verifyReleaseSignature: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package autodeployment
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// defaultHealthCheckTimeout bounds how long the post-swap canary is given to exit before the
+// update is treated as failed and rolled back.
+const defaultHealthCheckTimeout = 10 * time.Second
+
+// SetHealthCheck registers a canary check run against the new binary immediately after it's
+// swapped into place, before the process restarts into it. If fn returns an error, the swap
+// is automatically rolled back (the previous binary is restored) and the update is abandoned
+// for this tick. If neither SetHealthCheck nor SetHealthCheckArgs has been called, no health
+// check runs and the swap is never rolled back automatically; call Rollback yourself if you
+// detect a bad update out-of-band.
+func (u *Updater) SetHealthCheck(fn func(binPath string) error) {
+	u.healthCheck = fn
+}
+
+// SetHealthCheckArgs sets the arguments passed when exec'ing the new binary as a health
+// check, used only when no custom function has been registered via SetHealthCheck. Setting
+// non-empty args is what enables this exec-based check; by default no args are set and, unless
+// SetHealthCheck was called, no health check runs.
+func (u *Updater) SetHealthCheckArgs(args []string) {
+	u.healthCheckArgs = args
+}
+
+// Rollback restores the previous binary from its backup (created by the most recent
+// successful update) into place. It can be called at any time, including after a bad update
+// was detected out-of-band (e.g. from application-level monitoring), not just from within the
+// automatic health check.
+func (u *Updater) Rollback() error {
+	selfPath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	backupPath := selfPath + ".bak"
+	if _, err := os.Stat(backupPath); err != nil {
+		return fmt.Errorf("no backup available to roll back to: %w", err)
+	}
+
+	err = platformRestore(selfPath, backupPath)
+	u.emit(Event{Type: EventRollback, Err: err})
+	return err
+}
+
+// swapInstallError composes the error platformSwap reports when installing tmpPath over
+// selfPath fails and the fallback restore of backupPath also fails, so the caller learns
+// selfPath may now be missing entirely rather than just seeing the original install error.
+func swapInstallError(installErr, restoreErr error) error {
+	return fmt.Errorf("install new binary failed (%v) and restoring the original also failed, selfPath is missing: %w", installErr, restoreErr)
+}
+
+// swapBinary atomically installs tmpPath over selfPath, sets its permissions and mtime, and
+// (if a health check is configured) verifies the new binary runs before committing to it,
+// rolling back automatically on failure.
+func (u *Updater) swapBinary(selfPath, tmpPath string, mtime time.Time) error {
+	backupPath := selfPath + ".bak"
+	os.Remove(backupPath)
+
+	if err := platformSwap(selfPath, backupPath, tmpPath); err != nil {
+		return err
+	}
+
+	os.Chmod(selfPath, 0755)
+	os.Chtimes(selfPath, mtime, mtime)
+
+	if u.healthCheck == nil && len(u.healthCheckArgs) == 0 {
+		return nil
+	}
+
+	if err := u.runHealthCheck(selfPath); err != nil {
+		rbErr := platformRestore(selfPath, backupPath)
+		u.emit(Event{Type: EventRollback, Err: err})
+		if rbErr != nil {
+			return fmt.Errorf("health check failed (%v) and rollback also failed: %w", err, rbErr)
+		}
+		return fmt.Errorf("health check failed, rolled back: %w", err)
+	}
+
+	return nil
+}
+
+func (u *Updater) runHealthCheck(binPath string) error {
+	if u.healthCheck != nil {
+		return u.healthCheck(binPath)
+	}
+
+	args := u.healthCheckArgs
+	if len(args) == 0 {
+		args = []string{"--healthcheck"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHealthCheckTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binPath, args...)
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("health check timed out after %s", defaultHealthCheckTimeout)
+	}
+	return err
+}
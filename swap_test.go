@@ -0,0 +1,150 @@
+package autodeployment
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+// TestSwapBinarySucceeds verifies a plain swap (no health check configured) installs tmpPath
+// over selfPath and leaves the previous contents behind as a .bak.
+func TestSwapBinarySucceeds(t *testing.T) {
+	dir := t.TempDir()
+	selfPath := filepath.Join(dir, "app")
+	tmpPath := filepath.Join(dir, "app.download")
+	writeFile(t, selfPath, "old binary")
+	writeFile(t, tmpPath, "new binary")
+
+	u := &Updater{}
+	mtime := time.Now()
+	if err := u.swapBinary(selfPath, tmpPath, mtime); err != nil {
+		t.Fatalf("swapBinary: %v", err)
+	}
+
+	if got := readFile(t, selfPath); got != "new binary" {
+		t.Fatalf("selfPath = %q, want %q", got, "new binary")
+	}
+	if got := readFile(t, selfPath+".bak"); got != "old binary" {
+		t.Fatalf("backup = %q, want %q", got, "old binary")
+	}
+	if _, err := os.Stat(tmpPath); !os.IsNotExist(err) {
+		t.Fatalf("expected tmpPath to be moved away, stat err = %v", err)
+	}
+}
+
+// TestSwapBinaryRollsBackOnHealthCheckFailure verifies a failing health check restores the
+// previous binary and reports the health check's error rather than installing a bad update.
+func TestSwapBinaryRollsBackOnHealthCheckFailure(t *testing.T) {
+	dir := t.TempDir()
+	selfPath := filepath.Join(dir, "app")
+	tmpPath := filepath.Join(dir, "app.download")
+	writeFile(t, selfPath, "old binary")
+	writeFile(t, tmpPath, "new binary")
+
+	u := &Updater{}
+	u.SetHealthCheck(func(binPath string) error {
+		return errors.New("new binary refuses to start")
+	})
+
+	err := u.swapBinary(selfPath, tmpPath, time.Now())
+	if err == nil {
+		t.Fatal("expected swapBinary to report the health check failure")
+	}
+
+	if got := readFile(t, selfPath); got != "old binary" {
+		t.Fatalf("selfPath after rollback = %q, want original %q", got, "old binary")
+	}
+	if _, statErr := os.Stat(selfPath + ".bak"); !os.IsNotExist(statErr) {
+		t.Fatalf("expected backup to be consumed by rollback, stat err = %v", statErr)
+	}
+}
+
+// TestSwapBinarySucceedsWithPassingHealthCheck verifies a passing health check keeps the new
+// binary installed.
+func TestSwapBinarySucceedsWithPassingHealthCheck(t *testing.T) {
+	dir := t.TempDir()
+	selfPath := filepath.Join(dir, "app")
+	tmpPath := filepath.Join(dir, "app.download")
+	writeFile(t, selfPath, "old binary")
+	writeFile(t, tmpPath, "new binary")
+
+	u := &Updater{}
+	u.SetHealthCheck(func(binPath string) error { return nil })
+
+	if err := u.swapBinary(selfPath, tmpPath, time.Now()); err != nil {
+		t.Fatalf("swapBinary: %v", err)
+	}
+	if got := readFile(t, selfPath); got != "new binary" {
+		t.Fatalf("selfPath = %q, want %q", got, "new binary")
+	}
+}
+
+// TestPlatformRestoreWithoutBackupFails verifies platformRestore - the primitive both
+// Rollback and swapBinary's automatic rollback are built on - errors instead of silently
+// leaving selfPath untouched when there's no backup file to restore from.
+func TestPlatformRestoreWithoutBackupFails(t *testing.T) {
+	dir := t.TempDir()
+	selfPath := filepath.Join(dir, "app")
+	writeFile(t, selfPath, "current binary")
+
+	if err := platformRestore(selfPath, filepath.Join(dir, "app.bak")); err == nil {
+		t.Fatal("expected platformRestore to fail when the backup file doesn't exist")
+	}
+	if got := readFile(t, selfPath); got != "current binary" {
+		t.Fatalf("selfPath should be untouched after a failed restore, got %q", got)
+	}
+}
+
+// TestSwapInstallErrorWrapsBothFailures verifies the error platformSwap reports when the
+// install rename fails and the fallback restore rename *also* fails - it must surface the
+// restore failure, not just the original install error, since the caller can no longer assume
+// selfPath still exists once the restore itself has failed.
+func TestSwapInstallErrorWrapsBothFailures(t *testing.T) {
+	installErr := errors.New("install rename failed")
+	restoreErr := errors.New("restore rename failed")
+
+	err := swapInstallError(installErr, restoreErr)
+	if !strings.Contains(err.Error(), installErr.Error()) || !strings.Contains(err.Error(), restoreErr.Error()) {
+		t.Fatalf("expected the wrapped error to mention both failures, got: %v", err)
+	}
+	if !errors.Is(err, restoreErr) {
+		t.Fatalf("expected errors.Is to find the restore error, got: %v", err)
+	}
+}
+
+// TestPlatformSwapFailsWhenNothingToInstallOrRestore covers platformSwap's earliest failure,
+// not its double-failure path: if selfPath doesn't exist, the very first rename fails
+// before an install is even attempted, and platformSwap must still return an error rather than
+// silently doing nothing - the same code path that, further in, is responsible for reporting
+// it when an install failure's fallback restore also fails (see TestSwapInstallErrorWrapsBothFailures
+// for that wrapping behavior in isolation, since real renames can't deterministically force
+// both the install and the restore to fail in the same call).
+func TestPlatformSwapFailsWhenNothingToInstallOrRestore(t *testing.T) {
+	dir := t.TempDir()
+	selfPath := filepath.Join(dir, "app")
+	backupPath := selfPath + ".bak"
+	missingTmpPath := filepath.Join(dir, "app.download")
+
+	if err := platformSwap(selfPath, backupPath, missingTmpPath); err == nil {
+		t.Fatal("expected platformSwap to fail when selfPath doesn't exist")
+	}
+}
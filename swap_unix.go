@@ -0,0 +1,43 @@
+//go:build !windows
+
+package autodeployment
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// platformSwap moves the running executable aside to backupPath and installs tmpPath in its
+// place, fsync'ing the containing directory so the rename survives a crash before it's
+// flushed by the OS. If installing tmpPath fails, it attempts to restore the original binary
+// from backupPath; if that restore also fails, both errors are reported rather than leaving
+// the caller to assume selfPath still exists.
+func platformSwap(selfPath, backupPath, tmpPath string) error {
+	if err := os.Rename(selfPath, backupPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, selfPath); err != nil {
+		if rbErr := os.Rename(backupPath, selfPath); rbErr != nil {
+			return swapInstallError(err, rbErr)
+		}
+		return err
+	}
+	return fsyncDir(filepath.Dir(selfPath))
+}
+
+// platformRestore moves backupPath back into place at selfPath.
+func platformRestore(selfPath, backupPath string) error {
+	if err := os.Rename(backupPath, selfPath); err != nil {
+		return err
+	}
+	return fsyncDir(filepath.Dir(selfPath))
+}
+
+func fsyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
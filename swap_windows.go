@@ -0,0 +1,41 @@
+//go:build windows
+
+package autodeployment
+
+import "os"
+
+// platformSwap moves the running executable aside to backupPath and installs tmpPath in its
+// place. Windows allows renaming a running executable (just not deleting or overwriting it
+// in place), so the same rename-then-rename dance used on Unix works here too. If installing
+// tmpPath fails, it attempts to restore the original binary from backupPath; if that restore
+// also fails, both errors are reported rather than leaving the caller to assume selfPath
+// still exists.
+func platformSwap(selfPath, backupPath, tmpPath string) error {
+	if err := os.Rename(selfPath, backupPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, selfPath); err != nil {
+		if rbErr := os.Rename(backupPath, selfPath); rbErr != nil {
+			return swapInstallError(err, rbErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// platformRestore moves backupPath back into place at selfPath. selfPath may currently be the
+// running executable, so it's renamed aside first rather than overwritten directly.
+func platformRestore(selfPath, backupPath string) error {
+	badPath := selfPath + ".bad"
+	os.Remove(badPath)
+
+	if err := os.Rename(selfPath, badPath); err != nil {
+		return err
+	}
+	if err := os.Rename(backupPath, selfPath); err != nil {
+		os.Rename(badPath, selfPath)
+		return err
+	}
+	os.Remove(badPath)
+	return nil
+}
@@ -1,13 +1,13 @@
 package autodeployment
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -21,6 +21,29 @@ type ReleaseInfo struct {
 	DownloadURL string `json:"downloadUrl"`
 	// SHA256 is the SHA256 checksum of the release file.
 	SHA256 string `json:"sha256"`
+	// SignatureURL is the relative or absolute URL to a detached signature of the release file.
+	// When set, the signature is verified against a configured public key before the binary
+	// is installed, in addition to the SHA256 check above. If a public key has been configured
+	// (or RequireSignature has been called), a release that leaves this empty is rejected
+	// rather than silently skipping verification - see Updater.RequireSignature.
+	SignatureURL string `json:"signatureUrl"`
+	// SignatureAlgo selects the signature scheme used for SignatureURL: "ed25519", "minisign"
+	// or "rsa-pss". If empty, the default set by the first SetPublicKey or SetTrustedKeysFile
+	// call is used.
+	SignatureAlgo string `json:"signatureAlgo"`
+
+	// RolloutPercent restricts this release to a percentage of instances, for staged
+	// rollouts. 0 (the default) means unrestricted - every instance is eligible.
+	RolloutPercent int `json:"rolloutPercent"`
+	// RolloutSeed changes which instances land in the current wave; vary it between
+	// releases so the same instances aren't always picked first.
+	RolloutSeed string `json:"rolloutSeed"`
+	// NotBeforeEpochMs, if set, delays this release until the server-adjusted local time
+	// reaches this Unix millisecond timestamp, regardless of rollout bucket.
+	NotBeforeEpochMs int64 `json:"notBeforeEpochMs"`
+	// Cooldown is the number of minutes a freshly-upgraded instance should wait before
+	// accepting another update. 0 means no cooldown.
+	Cooldown int64 `json:"cooldownMinutes"`
 }
 
 // TimeInfo contains timing information for debugging time synchronization issues.
@@ -44,19 +67,85 @@ type Updater struct {
 	running          bool
 	stopChan         chan struct{}
 	serverTimeOffset int64
+
+	defaultSigAlgo   string
+	publicKeys       map[string][]byte
+	trustedKeys      map[string][]byte
+	requireSignature bool
+
+	restartStrategy    RestartStrategy
+	beforeRestartHooks []func() error
+	listenAddrs        []string
+
+	releaseSource ReleaseSource
+
+	healthCheck     func(binPath string) error
+	healthCheckArgs []string
+
+	progressReporter ProgressReporter
+	maxDownloadRate  int64
+
+	instanceID string
+
+	ctx        context.Context
+	httpClient *http.Client
+	hooks      []EventHook
+	telemetry  bool
 }
 
 // New creates a new Updater instance.
 //
 // The uuid and key are obtained from your deployment API project settings.
 func New(uuid, key string) *Updater {
-	return &Updater{
+	return NewWithContext(context.Background(), uuid, key)
+}
+
+// NewWithContext creates a new Updater instance whose HTTP calls are bound to ctx until
+// StartContext is called with a different one. Use this when the host application already
+// has a root context it wants update checks to respect (for cancellation, deadlines, or
+// request-scoped values such as trace IDs).
+func NewWithContext(ctx context.Context, uuid, key string) *Updater {
+	u := &Updater{
 		apiRoot:        "https://api.insights-api.top/deployment/",
 		updateInterval: 30 * time.Second,
 		projectUUID:    uuid,
 		projectKey:     key,
 		stopChan:       make(chan struct{}),
+		ctx:            ctx,
+		httpClient:     &http.Client{Timeout: 60 * time.Second},
 	}
+	u.releaseSource = &apiReleaseSource{u: u}
+	return u
+}
+
+// SetHTTPClient overrides the *http.Client used for all requests the Updater makes
+// (time sync, release checks, downloads). Default: a client with a 60s timeout.
+func (u *Updater) SetHTTPClient(client *http.Client) {
+	u.httpClient = client
+}
+
+// AddHook registers an EventHook notified of update lifecycle events (check started, check
+// failed, up to date, update available, download progress, hash mismatch, signature rejection,
+// swap completed, rollback). Hooks are called synchronously, in registration order, from
+// whatever goroutine is running the check - keep them fast and non-blocking.
+func (u *Updater) AddHook(h EventHook) {
+	u.hooks = append(u.hooks, h)
+}
+
+// SetTelemetry enables a small JSON POST back to the deployment API after each check
+// (the SHA256 of the latest release the check fetched, OS/arch, and outcome), so operators can
+// see fleet-wide update state. The running binary itself is not hashed for this report.
+// Only the default API release source supports this; it's a no-op with other sources.
+// Default: disabled.
+func (u *Updater) SetTelemetry(enabled bool) {
+	u.telemetry = enabled
+}
+
+// SetReleaseSource overrides where release metadata and binaries come from. By default,
+// Updater uses its own deployment API (the same one configured via SetAPIRoot); call this
+// to point at a GitHubReleaseSource, a StaticJSONSource, or a custom implementation.
+func (u *Updater) SetReleaseSource(src ReleaseSource) {
+	u.releaseSource = src
 }
 
 // SetAPIRoot sets the base URL for the deployment API.
@@ -77,13 +166,24 @@ func (u *Updater) SetUpdateInterval(interval time.Duration) {
 // It first synchronizes time with the server, then starts checking for updates
 // in a background goroutine at the configured interval.
 func (u *Updater) Start() error {
+	return u.StartContext(u.ctx)
+}
+
+// StartContext is like Start, but binds subsequent HTTP calls (time sync, release checks,
+// downloads, telemetry) to ctx instead of whatever context the Updater was created with.
+// The background checker goroutine stops if ctx is cancelled, in addition to Stop.
+func (u *Updater) StartContext(ctx context.Context) error {
 	if u.projectUUID == "" || u.projectKey == "" {
 		return fmt.Errorf("missing project UUID or key")
 	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	u.ctx = ctx
 
 	u.SyncTime()
 	u.running = true
-	go u.loop()
+	go u.loop(ctx)
 	return nil
 }
 
@@ -105,7 +205,12 @@ func (u *Updater) SyncTime() {
 // GetServerTime retrieves the current time from the deployment API.
 // Returns Unix milliseconds, or 0 on error.
 func (u *Updater) GetServerTime() int64 {
-	resp, err := http.Get(u.apiRoot + "/api/public/time")
+	req, err := http.NewRequestWithContext(u.ctx, http.MethodGet, u.apiRoot+"/api/public/time", nil)
+	if err != nil {
+		return 0
+	}
+
+	resp, err := u.httpClient.Do(req)
 	if err != nil {
 		return 0
 	}
@@ -146,37 +251,47 @@ func (u *Updater) GetTimeInfo() TimeInfo {
 	}
 }
 
-func (u *Updater) loop() {
+func (u *Updater) loop(ctx context.Context) {
 	ticker := time.NewTicker(u.updateInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			u.checkAndUpdate()
+			u.checkAndUpdate(ctx)
+		case <-ctx.Done():
+			return
 		case <-u.stopChan:
 			return
 		}
 	}
 }
 
-func (u *Updater) checkAndUpdate() {
+func (u *Updater) checkAndUpdate(ctx context.Context) {
+	u.emit(Event{Type: EventCheckStarted})
+
 	selfPath, err := os.Executable()
 	if err != nil {
 		return
 	}
 
-	release, err := u.fetchReleaseInfo()
+	release, err := u.releaseSource.FetchLatest(ctx)
 	if err != nil {
+		u.emit(Event{Type: EventCheckFailed, Err: fmt.Errorf("fetch latest release: %w", err)})
+		u.sendTelemetry(ctx, nil, "fetch_failed")
 		return
 	}
 
 	if release.SHA256 == "" {
+		u.emit(Event{Type: EventCheckSkipped, Release: release, Err: fmt.Errorf("release has no SHA256 checksum")})
+		u.sendTelemetry(ctx, release, "skipped_no_sha256")
 		return
 	}
 
 	info, statErr := os.Stat(selfPath)
 	if statErr != nil {
+		u.emit(Event{Type: EventCheckFailed, Release: release, Err: fmt.Errorf("stat running binary: %w", statErr)})
+		u.sendTelemetry(ctx, release, "stat_failed")
 		return
 	}
 
@@ -184,98 +299,97 @@ func (u *Updater) checkAndUpdate() {
 	adjustedMtime := localMtime + u.serverTimeOffset
 
 	if adjustedMtime >= release.LastModifiedEpochMs {
+		u.emit(Event{Type: EventUpToDate, Release: release})
+		u.sendTelemetry(ctx, release, "up_to_date")
+		return
+	}
+
+	if u.rolloutBlockReason(release, adjustedMtime) != "" {
+		u.sendTelemetry(ctx, release, "rollout_blocked")
 		return
 	}
 
-	downloadURL := u.resolveURL(release.DownloadURL)
+	u.emit(Event{Type: EventUpdateAvailable, Release: release})
+
 	tmpPath := selfPath + ".download"
 	os.Remove(tmpPath)
 
-	if err := u.download(downloadURL, tmpPath); err != nil {
+	if err := u.downloadRelease(ctx, release, tmpPath); err != nil {
 		os.Remove(tmpPath)
+		u.emit(Event{Type: EventCheckFailed, Release: release, Err: fmt.Errorf("download release: %w", err)})
+		u.sendTelemetry(ctx, release, "download_failed")
 		return
 	}
 
 	sha256Hash, err := calculateSHA256(tmpPath)
 	if err != nil {
 		os.Remove(tmpPath)
+		u.emit(Event{Type: EventCheckFailed, Release: release, Err: fmt.Errorf("calculate sha256: %w", err)})
+		u.sendTelemetry(ctx, release, "hash_failed")
 		return
 	}
 
 	if !strings.EqualFold(release.SHA256, sha256Hash) {
 		os.Remove(tmpPath)
+		u.emit(Event{Type: EventHashMismatch, Release: release})
+		u.sendTelemetry(ctx, release, "hash_mismatch")
 		return
 	}
 
-	if !u.verify(sha256Hash) {
+	if verifier, ok := u.releaseSource.(releaseHashVerifier); ok && !verifier.VerifyHash(ctx, sha256Hash) {
 		os.Remove(tmpPath)
+		u.emit(Event{Type: EventVerifyRejected, Release: release})
+		u.sendTelemetry(ctx, release, "verify_rejected")
 		return
 	}
 
-	backupPath := selfPath + ".bak"
-	os.Remove(backupPath)
-	os.Rename(selfPath, backupPath)
-	os.Rename(tmpPath, selfPath)
-
-	os.Chmod(selfPath, 0755)
-
-	mtime := time.UnixMilli(release.LastModifiedEpochMs)
-	if err := os.Chtimes(selfPath, mtime, mtime); err != nil {
-	}
-
-	os.Exit(0)
-}
-
-func (u *Updater) fetchReleaseInfo() (*ReleaseInfo, error) {
-	reqURL := fmt.Sprintf("%s/api/public/projects/%s/release?key=%s",
-		u.apiRoot, url.PathEscape(u.projectUUID), url.PathEscape(u.projectKey))
-
-	resp, err := http.Get(reqURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("http %d", resp.StatusCode)
+	if err := u.verifyReleaseSignature(ctx, tmpPath, release); err != nil {
+		os.Remove(tmpPath)
+		u.emit(Event{Type: EventVerifyRejected, Release: release, Err: err})
+		u.sendTelemetry(ctx, release, "signature_rejected")
+		return
 	}
 
-	var info ReleaseInfo
-	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
-		return nil, err
+	mtime := time.UnixMilli(release.LastModifiedEpochMs)
+	if err := u.swapBinary(selfPath, tmpPath, mtime); err != nil {
+		u.sendTelemetry(ctx, release, "update_failed")
+		return
 	}
 
-	if info.LastModifiedEpochMs <= 0 || info.DownloadURL == "" {
-		return nil, fmt.Errorf("invalid release info")
-	}
+	u.emit(Event{Type: EventSwapped, Release: release})
+	u.sendTelemetry(ctx, release, "updated")
 
-	return &info, nil
+	u.restart()
 }
 
-func (u *Updater) verify(sha256 string) bool {
-	reqURL := fmt.Sprintf("%s/api/public/projects/%s/verify?key=%s&sha256=%s",
-		u.apiRoot, url.PathEscape(u.projectUUID), url.PathEscape(u.projectKey), url.PathEscape(sha256))
-
-	resp, err := http.Get(reqURL)
+// downloadRelease writes the release source's download stream to destPath. The file is
+// opened without truncation so a ReleaseSource that supports resumable downloads (such as
+// the default API source) can pick up where a prior attempt within the same check left off.
+// Throughput limiting and progress reporting are passed down via DownloadOptions so every
+// ReleaseSource gets them, not just the default API source.
+func (u *Updater) downloadRelease(ctx context.Context, release *ReleaseInfo, destPath string) error {
+	file, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		return false
+		return err
 	}
-	defer resp.Body.Close()
+	defer file.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-	return strings.Contains(string(body), `"ok":true`) || strings.Contains(string(body), `"ok": true`)
+	opts := DownloadOptions{
+		MaxRate:  u.maxDownloadRate,
+		Reporter: u.effectiveProgressReporter(release),
+	}
+	return u.releaseSource.Download(ctx, release, file, opts)
 }
 
-func (u *Updater) download(downloadURL, destPath string) error {
-	req, err := http.NewRequest("GET", downloadURL, nil)
+func (u *Updater) download(ctx context.Context, downloadURL, destPath string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("User-Agent", "AutoDeploymentUpdater/1.0")
 	req.Header.Set("X-Project-Key", u.projectKey)
 
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := u.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -291,10 +405,34 @@ func (u *Updater) download(downloadURL, destPath string) error {
 	}
 	defer file.Close()
 
-	io.Copy(file, resp.Body)
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		return err
+	}
 	return nil
 }
 
+// verifyReleaseSignature downloads and checks release's detached signature against tmpPath,
+// when one is configured. A release with no SignatureURL is only accepted when
+// signatureRequired reports false - see Updater.RequireSignature for why that fails closed.
+func (u *Updater) verifyReleaseSignature(ctx context.Context, tmpPath string, release *ReleaseInfo) error {
+	if release.SignatureURL == "" {
+		if u.signatureRequired() {
+			return fmt.Errorf("signature verification is required but release has no SignatureURL")
+		}
+		return nil
+	}
+
+	sigPath := tmpPath + ".sig"
+	os.Remove(sigPath)
+	defer os.Remove(sigPath)
+
+	if err := u.download(ctx, u.resolveURL(release.SignatureURL), sigPath); err != nil {
+		return err
+	}
+
+	return u.verifySignature(tmpPath, sigPath, release.SignatureAlgo)
+}
+
 func (u *Updater) resolveURL(maybeRelative string) string {
 	if strings.HasPrefix(maybeRelative, "http://") || strings.HasPrefix(maybeRelative, "https://") {
 		return maybeRelative
@@ -0,0 +1,159 @@
+package autodeployment
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+// fakeReleaseSource is a minimal ReleaseSource used to drive checkAndUpdate end to end without
+// a real deployment API.
+type fakeReleaseSource struct {
+	release     *ReleaseInfo
+	content     []byte
+	fetchErr    error
+	downloadErr error
+}
+
+func (s *fakeReleaseSource) FetchLatest(ctx context.Context) (*ReleaseInfo, error) {
+	if s.fetchErr != nil {
+		return nil, s.fetchErr
+	}
+	return s.release, nil
+}
+
+func (s *fakeReleaseSource) Download(ctx context.Context, rel *ReleaseInfo, w io.Writer, opts DownloadOptions) error {
+	if s.downloadErr != nil {
+		return s.downloadErr
+	}
+	_, err := w.Write(s.content)
+	return err
+}
+
+// recordingHook collects every Event it's notified of, for assertions in tests.
+type recordingHook struct {
+	events []Event
+}
+
+func (h *recordingHook) HandleEvent(e Event) {
+	h.events = append(h.events, e)
+}
+
+func (h *recordingHook) has(t EventType) bool {
+	for _, e := range h.events {
+		if e.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// TestCheckAndUpdateRejectsUnsignedReleaseWhenSignatureRequired is an integration test of the
+// fail-closed signature policy: once a public key is configured, a release that passes its
+// SHA256 check but omits SignatureURL must still be rejected by checkAndUpdate, and the
+// downloaded temp file must not be left behind.
+func TestCheckAndUpdateRejectsUnsignedReleaseWhenSignatureRequired(t *testing.T) {
+	selfPath, err := os.Executable()
+	if err != nil {
+		t.Fatalf("os.Executable: %v", err)
+	}
+	tmpPath := selfPath + ".download"
+	os.Remove(tmpPath)
+	defer os.Remove(tmpPath)
+
+	content := []byte("new release bytes")
+	sum := sha256.Sum256(content)
+
+	hook := &recordingHook{}
+	u := &Updater{httpClient: http.DefaultClient}
+	u.SetPublicKey("ed25519", make([]byte, ed25519.PublicKeySize))
+	u.AddHook(hook)
+	u.releaseSource = &fakeReleaseSource{
+		release: &ReleaseInfo{
+			LastModifiedEpochMs: time.Now().Add(24 * time.Hour).UnixMilli(),
+			DownloadURL:         "unused",
+			SHA256:              hex.EncodeToString(sum[:]),
+		},
+		content: content,
+	}
+
+	u.checkAndUpdate(context.Background())
+
+	if _, err := os.Stat(tmpPath); !os.IsNotExist(err) {
+		t.Fatalf("expected the downloaded temp file to be cleaned up, stat err = %v", err)
+	}
+	if !hook.has(EventVerifyRejected) {
+		t.Fatal("expected a VerifyRejected event when a signature is required but the release has no SignatureURL")
+	}
+	if hook.has(EventSwapped) {
+		t.Fatal("expected the unsigned release not to be installed")
+	}
+}
+
+// TestCheckAndUpdateAllowsUnsignedReleaseWhenNoKeyConfigured calls verifyReleaseSignature,
+// the signature gate checkAndUpdate runs, directly and verifies it stays out of the way for
+// the common case where no key has ever been configured: an unsigned release passes it.
+func TestCheckAndUpdateAllowsUnsignedReleaseWhenNoKeyConfigured(t *testing.T) {
+	u := &Updater{httpClient: http.DefaultClient}
+	release := &ReleaseInfo{}
+
+	if err := u.verifyReleaseSignature(context.Background(), "unused", release); err != nil {
+		t.Fatalf("expected no signature policy to let an unsigned release through, got: %v", err)
+	}
+}
+
+// TestCheckAndUpdateEmitsCheckFailedOnFetchError verifies a failing ReleaseSource.FetchLatest -
+// the single most common failure for a production fleet polling a flaky or down update API -
+// is surfaced to hooks and telemetry instead of silently returning.
+func TestCheckAndUpdateEmitsCheckFailedOnFetchError(t *testing.T) {
+	hook := &recordingHook{}
+	u := &Updater{httpClient: http.DefaultClient}
+	u.AddHook(hook)
+	u.releaseSource = &fakeReleaseSource{fetchErr: errors.New("api unreachable")}
+
+	u.checkAndUpdate(context.Background())
+
+	if !hook.has(EventCheckFailed) {
+		t.Fatal("expected an EventCheckFailed event when FetchLatest fails")
+	}
+}
+
+// TestCheckAndUpdateEmitsCheckFailedOnDownloadError mirrors
+// TestCheckAndUpdateEmitsCheckFailedOnFetchError for a failing download.
+func TestCheckAndUpdateEmitsCheckFailedOnDownloadError(t *testing.T) {
+	selfPath, err := os.Executable()
+	if err != nil {
+		t.Fatalf("os.Executable: %v", err)
+	}
+	tmpPath := selfPath + ".download"
+	os.Remove(tmpPath)
+	defer os.Remove(tmpPath)
+
+	hook := &recordingHook{}
+	u := &Updater{httpClient: http.DefaultClient}
+	u.AddHook(hook)
+	u.releaseSource = &fakeReleaseSource{
+		release: &ReleaseInfo{
+			LastModifiedEpochMs: time.Now().Add(24 * time.Hour).UnixMilli(),
+			DownloadURL:         "unused",
+			SHA256:              "deadbeef",
+		},
+		downloadErr: errors.New("connection reset"),
+	}
+
+	u.checkAndUpdate(context.Background())
+
+	if !hook.has(EventCheckFailed) {
+		t.Fatal("expected an EventCheckFailed event when the download fails")
+	}
+	if _, err := os.Stat(tmpPath); !os.IsNotExist(err) {
+		t.Fatalf("expected the downloaded temp file to be cleaned up, stat err = %v", err)
+	}
+}